@@ -0,0 +1,37 @@
+package deepequalexplained
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// byteSlicesOf returns the underlying bytes of v1 and v2 when both are a
+// []byte/[]uint8, or an addressable [N]byte/[N]uint8, so the caller can
+// fall back to a direct byte compare instead of per-element reflection.
+// It reports ok == false whenever that fast path does not apply, e.g. for
+// non-addressable byte arrays, where Bytes would otherwise panic.
+func byteSlicesOf(v1, v2 reflect.Value) (b1, b2 []byte, ok bool) {
+	if v1.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, nil, false
+	}
+	if v1.Kind() == reflect.Array && (!v1.CanAddr() || !v2.CanAddr()) {
+		return nil, nil, false
+	}
+	return v1.Bytes(), v2.Bytes(), true
+}
+
+// compareBytes reports the first index at which b1 and b2 differ, if any,
+// as a ValueMismatch Diff at path+[index]. b1 and b2 are assumed to be the
+// same length, as callers only reach here after the length/type of v1 and
+// v2 have already been confirmed equal.
+func compareBytes(b1, b2 []byte, path []PathStep, sink *diffSink) {
+	if bytes.Equal(b1, b2) {
+		return
+	}
+	for i := 0; i < len(b1); i++ {
+		if b1[i] != b2[i] {
+			sink.add(append(path, PathStep{Kind: StepIndex, Index: i}), ValueMismatch, b1[i], b2[i])
+			return
+		}
+	}
+}
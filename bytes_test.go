@@ -0,0 +1,60 @@
+package deepequalexplained
+
+import "testing"
+
+func TestByteSliceFastPath(t *testing.T) {
+	a := make([]byte, 1024)
+	b := make([]byte, 1024)
+	for i := range a {
+		a[i] = byte(i)
+		b[i] = byte(i)
+	}
+	if err := DeepEqualExplained(a, b); err != nil {
+		t.Fatalf("expected equal, got %v", err)
+	}
+	b[500] = a[500] + 1
+	if err := DeepEqualExplained(a, b); err == nil {
+		t.Fatal("expected mismatch at differing byte")
+	}
+}
+
+func TestByteArrayFastPath(t *testing.T) {
+	var a, b [32]byte
+	a[10] = 7
+	b[10] = 7
+	if err := DeepEqualExplained(a, b); err != nil {
+		t.Fatalf("expected equal, got %v", err)
+	}
+	b[10] = 8
+	if err := DeepEqualExplained(a, b); err == nil {
+		t.Fatal("expected mismatch")
+	}
+}
+
+func BenchmarkByteSliceEqual(b *testing.B) {
+	x := make([]byte, 1<<20)
+	y := make([]byte, 1<<20)
+	copy(y, x)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DeepEqualExplained(x, y); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMapStringStringEqual(b *testing.B) {
+	x := make(map[string]string, 1000)
+	y := make(map[string]string, 1000)
+	for i := 0; i < 1000; i++ {
+		k := string(rune('a' + i%26))
+		x[k+string(rune(i))] = "value"
+		y[k+string(rune(i))] = "value"
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := DeepEqualExplained(x, y); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
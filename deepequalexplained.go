@@ -19,149 +19,319 @@ type visit struct {
 	typ reflect.Type
 }
 
-func deepValueEqual(v1, v2 reflect.Value, visited map[visit]bool, depth int) error {
-	if !v1.IsValid() || !v2.IsValid() {
-		if v1.IsValid() == v2.IsValid() {
-			return nil
-		} else if !v1.IsValid() {
-			return fmt.Errorf(" in x is invalid but in y is not")
-		} else {
-			return fmt.Errorf(" in y is invalid but in x is not")
+// hard reports whether v1 and v2 are a kind of value that can sit on a
+// cycle: non-nil pointers, maps, and slices. Arrays and structs have no
+// identity of their own, so a cycle always passes through one of these
+// kinds on the way back to itself. This mirrors the newer Go stdlib
+// reflect.DeepEqual, which keys cycle detection off the referenced value
+// rather than the addressability of the reflect.Value holding it.
+func hard(v1, v2 reflect.Value) bool {
+	switch v1.Kind() {
+	case reflect.Ptr:
+		if !mayContainPointer(v1.Type().Elem()) {
+			return false
+		}
+		return !v1.IsNil() && !v2.IsNil()
+	case reflect.Map:
+		if !mayContainPointer(v1.Type().Elem()) && !mayContainPointer(v1.Type().Key()) {
+			return false
+		}
+		return !v1.IsNil() && !v2.IsNil()
+	case reflect.Slice:
+		if !mayContainPointer(v1.Type().Elem()) {
+			return false
 		}
+		return !v1.IsNil() && !v2.IsNil()
 	}
-	if v1.Type() != v2.Type() {
-		return fmt.Errorf(" has different types, where in x is %v but in y is %v", v1.Type().Name(), v2.Type().Name())
+	return false
+}
+
+// markVisited records that v1 and v2 are being compared, and reports
+// whether that pair (keyed by the pointers they reference, not by the
+// address of the reflect.Value itself) was already seen earlier in the
+// recursion - i.e. whether v1/v2 closes a cycle that should be treated as
+// equal without further recursion.
+func markVisited(v1, v2 reflect.Value, visited map[visit]bool) bool {
+	if !hard(v1, v2) {
+		return false
+	}
+	addr1 := unsafe.Pointer(v1.Pointer())
+	addr2 := unsafe.Pointer(v2.Pointer())
+	if uintptr(addr1) > uintptr(addr2) {
+		// Canonicalize order to reduce number of entries in visited.
+		// Assumes non-moving garbage collector.
+		addr1, addr2 = addr2, addr1
+	}
+
+	// Short circuit if references are already seen.
+	v := visit{addr1, addr2, v1.Type()}
+	if visited[v] {
+		return true
 	}
 
-	hard := func(k reflect.Kind) bool {
-		switch k {
-		case reflect.Array, reflect.Map, reflect.Slice, reflect.Struct:
-			return true
+	// Remember for later.
+	visited[v] = true
+	return false
+}
+
+// mayContainPointer approximates the stdlib notion of a type having
+// PtrBytes != 0: whether a value of type t could, directly or through a
+// field/element, hold a reference capable of taking part in a cycle
+// (Ptr, Map, Slice, Interface, Chan, Func). Plain-data kinds like String
+// are deliberately excluded - a string cannot point back into the graph
+// being compared - which lets hard() skip visited-map bookkeeping for
+// common large containers such as []string or map[string]string.
+func mayContainPointer(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		return true
+	case reflect.Array:
+		return t.Len() > 0 && mayContainPointer(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if mayContainPointer(t.Field(i).Type) {
+				return true
+			}
 		}
 		return false
+	default:
+		return false
 	}
+}
 
-	if v1.CanAddr() && v2.CanAddr() && hard(v1.Kind()) {
-		addr1 := unsafe.Pointer(v1.UnsafeAddr())
-		addr2 := unsafe.Pointer(v2.UnsafeAddr())
-		if uintptr(addr1) > uintptr(addr2) {
-			// Canonicalize order to reduce number of entries in visited.
-			// Assumes non-moving garbage collector.
-			addr1, addr2 = addr2, addr1
-		}
+// deepValueEqual walks v1 and v2 in lockstep, recording every mismatch it
+// finds as a Diff in sink, annotated with the path (relative to the
+// original root values) at which it was found. It stops descending into a
+// subtree as soon as sink is full.
+func deepValueEqual(v1, v2 reflect.Value, visited map[visit]bool, path []PathStep, equalities Equalities, cfg *config, sink *diffSink) {
+	if sink.full() {
+		return
+	}
 
-		// Short circuit if references are already seen.
-		typ := v1.Type()
-		v := visit{addr1, addr2, typ}
-		if visited[v] {
-			return nil
+	if !v1.IsValid() || !v2.IsValid() {
+		if v1.IsValid() != v2.IsValid() {
+			var x, y interface{}
+			if !v1.IsValid() {
+				y = true
+			} else {
+				x = true
+			}
+			sink.add(path, NilMismatch, x, y)
 		}
+		return
+	}
+	if v1.Type() != v2.Type() {
+		sink.add(path, TypeMismatch, v1.Type().Name(), v2.Type().Name())
+		return
+	}
 
-		// Remember for later.
-		visited[v] = true
+	// fv.Call panics on a reflect.Value obtained from an unexported field,
+	// so values reached that way (e.g. a *big.Int embedded unexported in a
+	// struct) skip the registered comparator and fall through to the
+	// generic comparison below instead.
+	if fv, ok := equalities.funcValueFor(v1.Type()); ok && v1.CanInterface() && v2.CanInterface() {
+		out := fv.Call([]reflect.Value{v1, v2})
+		if !out[0].Bool() {
+			sink.add(path, ValueMismatch, v1.Interface(), v2.Interface())
+		}
+		return
 	}
 
 	switch v1.Kind() {
 	case reflect.Array:
-		for i := 0; i < v1.Len(); i++ {
-			if err := deepValueEqual(v1.Index(i), v2.Index(i), visited, depth+1); err != nil {
-				return fmt.Errorf("[%d]%s", i, err.Error())
-			}
+		if b1, b2, ok := byteSlicesOf(v1, v2); ok {
+			compareBytes(b1, b2, path, sink)
+			return
+		}
+		for i := 0; i < v1.Len() && !sink.full(); i++ {
+			deepValueEqual(v1.Index(i), v2.Index(i), visited, append(path, PathStep{Kind: StepIndex, Index: i}), equalities, cfg, sink)
 		}
-		return nil
 	case reflect.Slice:
 		if v1.IsNil() != v2.IsNil() {
-			if v1.IsNil() {
-				return fmt.Errorf(" in x is nil but in y is not")
-			} else {
-				return fmt.Errorf(" in y is nil but in x is not")
-			}
+			sink.add(path, NilMismatch, nilMismatchValue(v1), nilMismatchValue(v2))
+			return
 		}
 		if v1.Len() != v2.Len() {
-			return fmt.Errorf(" do not have the same length")
+			sink.add(path, LengthMismatch, v1.Len(), v2.Len())
+			return
 		}
 		if v1.Pointer() == v2.Pointer() {
-			return nil
+			return
 		}
-		for i := 0; i < v1.Len(); i++ {
-			if err := deepValueEqual(v1.Index(i), v2.Index(i), visited, depth+1); err != nil {
-				return fmt.Errorf("[%d]%s", i, err.Error())
-			}
+		if markVisited(v1, v2, visited) {
+			return
+		}
+		if cfg.unorderedSlice(v1.Type()) {
+			unorderedSliceEqual(v1, v2, visited, path, equalities, cfg, sink)
+			return
+		}
+		if b1, b2, ok := byteSlicesOf(v1, v2); ok {
+			compareBytes(b1, b2, path, sink)
+			return
+		}
+		for i := 0; i < v1.Len() && !sink.full(); i++ {
+			deepValueEqual(v1.Index(i), v2.Index(i), visited, append(path, PathStep{Kind: StepIndex, Index: i}), equalities, cfg, sink)
 		}
-		return nil
 	case reflect.Interface:
 		if v1.IsNil() || v2.IsNil() {
-			if v1.IsNil() == v2.IsNil() {
-				return nil
-			} else {
-				return fmt.Errorf(" do not have the same interface")
+			if v1.IsNil() != v2.IsNil() {
+				sink.add(path, NilMismatch, nilMismatchValue(v1), nilMismatchValue(v2))
 			}
+			return
 		}
-		if err := deepValueEqual(v1.Elem(), v2.Elem(), visited, depth+1); err != nil {
-			return fmt.Errorf("(Interface)%s", err.Error())
-		}
-		return nil
+		deepValueEqual(v1.Elem(), v2.Elem(), visited, append(path, PathStep{Kind: StepInterface}), equalities, cfg, sink)
 	case reflect.Ptr:
 		if v1.Pointer() == v2.Pointer() {
-			return nil
+			return
 		}
-		if err := deepValueEqual(v1.Elem(), v2.Elem(), visited, depth+1); err != nil {
-			return fmt.Errorf("(Ptr)%s", err.Error())
+		if markVisited(v1, v2, visited) {
+			return
 		}
-		return nil
+		deepValueEqual(v1.Elem(), v2.Elem(), visited, append(path, PathStep{Kind: StepPointer}), equalities, cfg, sink)
 	case reflect.Struct:
-		for i, n := 0, v1.NumField(); i < n; i++ {
-			if err := deepValueEqual(v1.Field(i), v2.Field(i), visited, depth+1); err != nil {
-				return fmt.Errorf(".%s%s", v1.Type().Field(i).Name, err.Error())
+		for i, n := 0, v1.NumField(); i < n && !sink.full(); i++ {
+			field := v1.Type().Field(i)
+			if cfg.ignoresField(field) {
+				continue
 			}
+			deepValueEqual(v1.Field(i), v2.Field(i), visited, append(path, PathStep{Kind: StepField, Field: field.Name}), equalities, cfg, sink)
 		}
-		return nil
 	case reflect.Map:
 		if v1.IsNil() != v2.IsNil() {
-			if v1.IsNil() {
-				return fmt.Errorf(" are not equal, where in x is nil but in y is not")
-			} else {
-				return fmt.Errorf(" are not equal, where in y is nil but in x is not")
-			}
+			sink.add(path, NilMismatch, nilMismatchValue(v1), nilMismatchValue(v2))
+			return
 		}
 		if v1.Len() != v2.Len() {
-			return fmt.Errorf(" do not have the same length, where in x is %d but in y is %d", v1.Len(), v2.Len())
+			sink.add(path, LengthMismatch, v1.Len(), v2.Len())
+			return
 		}
 		if v1.Pointer() == v2.Pointer() {
-			return nil
+			return
+		}
+		if markVisited(v1, v2, visited) {
+			return
 		}
 		for _, k := range v1.MapKeys() {
+			if sink.full() {
+				return
+			}
 			val1 := v1.MapIndex(k)
 			val2 := v2.MapIndex(k)
+			keyPath := append(path, PathStep{Kind: StepMapKey, Key: k.Interface()})
 			if !val1.IsValid() {
-				return fmt.Errorf("[%v] is invalid in x", k)
+				sink.add(keyPath, MapKeyMissing, nil, true)
 			} else if !val2.IsValid() {
-				return fmt.Errorf("[%v] is invalid in y", k)
-			} else if err := deepValueEqual(v1.MapIndex(k), v2.MapIndex(k), visited, depth+1); err != nil {
-				return fmt.Errorf("[%v]%s", k, err.Error())
+				sink.add(keyPath, MapKeyMissing, true, nil)
+			} else {
+				deepValueEqual(val1, val2, visited, keyPath, equalities, cfg, sink)
 			}
 		}
-		return nil
 	case reflect.Func:
 		if v1.IsNil() && v2.IsNil() {
-			return nil
+			return
 		}
 		// Can't do better than this:
-		return fmt.Errorf(" has different func")
+		sink.add(path, FuncNonNil, nil, nil)
 	default:
 		// Trying to compare between two values
-		if v1.Kind() == reflect.Float64 && math.IsNaN(v1.Float()) {
-			return fmt.Errorf(" in x is NaN float")
-		} else if v2.Kind() == reflect.Float64 && math.IsNaN(v2.Float()) {
-			return fmt.Errorf(" in y is NaN float")
-		} else if fmt.Sprintf("%T", v1) != fmt.Sprintf("%T", v2) {
-			return fmt.Errorf(" have different types, where in x is %T but in y is %T", v1, v2)
-		} else if fmt.Sprintf("%v", v1) != fmt.Sprintf("%v", v2) {
-			return fmt.Errorf(" are not equal, where in x is %v but in y is %v", v1, v2)
+		if v1.Kind() == reflect.Float32 || v1.Kind() == reflect.Float64 {
+			x1, x2 := v1.Float(), v2.Float()
+			if math.IsNaN(x1) || math.IsNaN(x2) {
+				if cfg != nil && cfg.nanEqual && math.IsNaN(x1) && math.IsNaN(x2) {
+					return
+				}
+				if math.IsNaN(x1) {
+					sink.add(path, NaNMismatch, true, nil)
+				} else {
+					sink.add(path, NaNMismatch, nil, true)
+				}
+				return
+			}
+			if cfg != nil && cfg.floatTolerance != nil {
+				if math.Abs(x1-x2) > *cfg.floatTolerance {
+					sink.add(path, ValueMismatch, x1, x2)
+				}
+				return
+			}
+		}
+		// v1.Type() == v2.Type() was already established above, and every
+		// kind reaching this default case (bool, int*, uint*, float*,
+		// complex*, string, chan, unsafe pointer) is comparable, so a
+		// direct interface comparison avoids the cost of formatting both
+		// values through fmt.Sprintf just to compare them byte for byte.
+		// Unexported fields fall back to the old %v-based comparison,
+		// since CanInterface is false for them.
+		if v1.CanInterface() && v2.CanInterface() {
+			if v1.Interface() != v2.Interface() {
+				sink.add(path, ValueMismatch, v1.Interface(), v2.Interface())
+			}
+			return
+		}
+		if s1, s2 := fmt.Sprintf("%v", v1), fmt.Sprintf("%v", v2); s1 != s2 {
+			sink.add(path, ValueMismatch, s1, s2)
 		}
+	}
+}
+
+// nilMismatchValue returns a non-nil marker for a nil reflect.Value (slice,
+// map, or interface) and nil otherwise, so Diff.X/Y can record which side
+// was nil without keeping a live reflect.Value around.
+func nilMismatchValue(v reflect.Value) interface{} {
+	if v.IsNil() {
 		return nil
 	}
+	return true
+}
 
+// unorderedSliceEqual compares v1 and v2, both slices of the same length,
+// as multisets: each element of x must have a distinct, recursively-equal
+// match somewhere in y. It reports the index of the first element of x
+// that has no remaining match in y.
+func unorderedSliceEqual(v1, v2 reflect.Value, visited map[visit]bool, path []PathStep, equalities Equalities, cfg *config, sink *diffSink) {
+	used := make([]bool, v2.Len())
+	for i := 0; i < v1.Len(); i++ {
+		found := false
+		for j := 0; j < v2.Len(); j++ {
+			if used[j] {
+				continue
+			}
+			if ok, trial := valuesEqual(v1.Index(i), v2.Index(j), visited, equalities, cfg); ok {
+				// Only commit the pointer pairs this trial walked through
+				// once it is accepted as the match for x[i]; a failed
+				// trial's visited entries must not leak into the next
+				// candidate pair, or an unrelated pair sharing one of the
+				// same pointers would be short-circuited to "equal".
+				for k, v := range trial {
+					visited[k] = v
+				}
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			sink.add(append(path, PathStep{Kind: StepIndex, Index: i}), MapKeyMissing, true, nil)
+			return
+		}
+	}
+}
+
+// valuesEqual reports whether v1 and v2 are deeply equal, without
+// recording any Diff; it is used to probe candidate matches during
+// unordered slice comparison. It probes against a copy of visited seeded
+// from the caller's map, so cycles reachable through the probed elements
+// are still caught, but returns that copy rather than mutating visited
+// directly - the caller decides whether to keep the pointer pairs this
+// trial recorded, since a rejected candidate's bookkeeping must not
+// affect the next candidate probed against the same elements.
+func valuesEqual(v1, v2 reflect.Value, visited map[visit]bool, equalities Equalities, cfg *config) (bool, map[visit]bool) {
+	trial := make(map[visit]bool, len(visited))
+	for k, v := range visited {
+		trial[k] = v
+	}
+	probe := &diffSink{max: 1}
+	deepValueEqual(v1, v2, trial, nil, equalities, cfg, probe)
+	return len(probe.diffs) == 0, trial
 }
 
 func DeepEqualExplained(x, y interface{}) error {
@@ -179,9 +349,37 @@ func DeepEqualExplained(x, y interface{}) error {
 	if v1.Type() != v2.Type() {
 		return fmt.Errorf("values have different types, where in x is %v but in y is %v", v1.Type().Name(), v2.Type().Name())
 	}
-	if err := deepValueEqual(v1, v2, make(map[visit]bool), 0); err != nil {
-		return fmt.Errorf("values%s", err.Error())
-	} else {
-		return nil
+	sink := &diffSink{max: 1}
+	deepValueEqual(v1, v2, make(map[visit]bool), nil, nil, nil, sink)
+	if len(sink.diffs) > 0 {
+		return fmt.Errorf("values%s", sink.diffs[0].String())
+	}
+	return nil
+}
+
+// DeepEqualExplainedWithOptions behaves like DeepEqualExplained, but applies
+// the given Options to the comparison, e.g. to skip tagged struct fields,
+// tolerate small floating point differences, or treat slices as multisets.
+// With no options it behaves identically to DeepEqualExplained.
+func DeepEqualExplainedWithOptions(x, y interface{}, opts ...Option) error {
+	if x == nil || y == nil {
+		if x == y {
+			return nil
+		} else if x == nil {
+			return fmt.Errorf("x is nil while y is not")
+		} else {
+			return fmt.Errorf("y is nil while x is not")
+		}
+	}
+	v1 := reflect.ValueOf(x)
+	v2 := reflect.ValueOf(y)
+	if v1.Type() != v2.Type() {
+		return fmt.Errorf("values have different types, where in x is %v but in y is %v", v1.Type().Name(), v2.Type().Name())
+	}
+	sink := &diffSink{max: 1}
+	deepValueEqual(v1, v2, make(map[visit]bool), nil, nil, newConfig(opts), sink)
+	if len(sink.diffs) > 0 {
+		return fmt.Errorf("values%s", sink.diffs[0].String())
 	}
+	return nil
 }
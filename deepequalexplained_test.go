@@ -0,0 +1,89 @@
+package deepequalexplained
+
+import "testing"
+
+type simpleStruct struct {
+	A int
+	B string
+}
+
+type node struct {
+	Val  int
+	Next *node
+}
+
+func TestDeepEqualExplainedEqual(t *testing.T) {
+	x := simpleStruct{A: 1, B: "x"}
+	y := simpleStruct{A: 1, B: "x"}
+	if err := DeepEqualExplained(x, y); err != nil {
+		t.Fatalf("expected equal, got %v", err)
+	}
+}
+
+func TestDeepEqualExplainedFieldMismatch(t *testing.T) {
+	x := simpleStruct{A: 1, B: "x"}
+	y := simpleStruct{A: 1, B: "y"}
+	if err := DeepEqualExplained(x, y); err == nil {
+		t.Fatal("expected error for mismatched field")
+	}
+}
+
+func TestDeepEqualExplainedNil(t *testing.T) {
+	if err := DeepEqualExplained(nil, nil); err != nil {
+		t.Fatalf("expected nil == nil, got %v", err)
+	}
+	if err := DeepEqualExplained(nil, 1); err == nil {
+		t.Fatal("expected error comparing nil to non-nil")
+	}
+}
+
+func TestDeepEqualExplainedTypeMismatch(t *testing.T) {
+	if err := DeepEqualExplained(1, "1"); err == nil {
+		t.Fatal("expected error for different types")
+	}
+}
+
+func TestDeepEqualExplainedSliceLength(t *testing.T) {
+	if err := DeepEqualExplained([]int{1, 2}, []int{1, 2, 3}); err == nil {
+		t.Fatal("expected error for different slice lengths")
+	}
+}
+
+func TestDeepEqualExplainedMapKeyMissing(t *testing.T) {
+	x := map[string]int{"a": 1, "b": 2}
+	y := map[string]int{"a": 1, "c": 2}
+	if err := DeepEqualExplained(x, y); err == nil {
+		t.Fatal("expected error for mismatched map keys")
+	}
+}
+
+func TestDeepEqualExplainedFuncNonNil(t *testing.T) {
+	type withFunc struct {
+		F func()
+	}
+	x := withFunc{F: func() {}}
+	y := withFunc{F: func() {}}
+	if err := DeepEqualExplained(x, y); err == nil {
+		t.Fatal("expected error comparing two non-nil funcs")
+	}
+}
+
+func TestDeepEqualExplainedCyclicPointer(t *testing.T) {
+	a := &node{Val: 1}
+	a.Next = a
+	b := &node{Val: 1}
+	b.Next = b
+	if err := DeepEqualExplained(a, b); err != nil {
+		t.Fatalf("expected equal cyclic structs, got %v", err)
+	}
+}
+
+func TestDeepEqualExplainedCyclicThroughMapInterface(t *testing.T) {
+	m1 := map[string]interface{}{}
+	m1["self"] = m1
+	m2 := map[string]interface{}{}
+	m2["self"] = m2
+	if err := DeepEqualExplained(m1, m2); err != nil {
+		t.Fatalf("expected equal cyclic maps, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package deepequalexplained
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// PathStepKind identifies the shape of a single step in a Diff's Path.
+type PathStepKind int
+
+const (
+	// StepField descends into a named struct field.
+	StepField PathStepKind = iota
+	// StepIndex descends into a slice or array element.
+	StepIndex
+	// StepMapKey descends into a map value by key.
+	StepMapKey
+	// StepInterface unwraps an interface value.
+	StepInterface
+	// StepPointer dereferences a pointer.
+	StepPointer
+)
+
+// PathStep is one segment of the path from the compared root values down to
+// the point where a Diff was found.
+type PathStep struct {
+	Kind  PathStepKind
+	Field string      // set when Kind == StepField
+	Index int         // set when Kind == StepIndex
+	Key   interface{} // set when Kind == StepMapKey
+}
+
+func (s PathStep) String() string {
+	switch s.Kind {
+	case StepField:
+		return "." + s.Field
+	case StepIndex:
+		return fmt.Sprintf("[%d]", s.Index)
+	case StepMapKey:
+		return fmt.Sprintf("[%v]", s.Key)
+	case StepInterface:
+		return "(Interface)"
+	case StepPointer:
+		return "(Ptr)"
+	default:
+		return ""
+	}
+}
+
+// Reason classifies why two values at a given Path were found unequal.
+type Reason string
+
+const (
+	TypeMismatch   Reason = "TypeMismatch"
+	LengthMismatch Reason = "LengthMismatch"
+	NilMismatch    Reason = "NilMismatch"
+	ValueMismatch  Reason = "ValueMismatch"
+	NaNMismatch    Reason = "NaN"
+	FuncNonNil     Reason = "FuncNonNil"
+	MapKeyMissing  Reason = "MapKeyMissing"
+)
+
+// Diff describes a single mismatch found between two values, at the given
+// Path below the compared roots, together with the values involved.
+type Diff struct {
+	Path   []PathStep
+	Reason Reason
+	X, Y   interface{}
+}
+
+// String renders the Diff the same way DeepEqualExplained renders the first
+// Diff it finds, minus the "values" root prefix.
+func (d Diff) String() string {
+	var path strings.Builder
+	for _, step := range d.Path {
+		path.WriteString(step.String())
+	}
+	switch d.Reason {
+	case TypeMismatch:
+		return fmt.Sprintf("%s has different types, where in x is %v but in y is %v", path.String(), d.X, d.Y)
+	case LengthMismatch:
+		return fmt.Sprintf("%s do not have the same length, where in x is %v but in y is %v", path.String(), d.X, d.Y)
+	case NilMismatch:
+		if d.X == nil {
+			return fmt.Sprintf("%s in x is nil but in y is not", path.String())
+		}
+		return fmt.Sprintf("%s in y is nil but in x is not", path.String())
+	case NaNMismatch:
+		if d.X == nil {
+			return fmt.Sprintf("%s in x is NaN float", path.String())
+		}
+		return fmt.Sprintf("%s in y is NaN float", path.String())
+	case FuncNonNil:
+		return fmt.Sprintf("%s has different func", path.String())
+	case MapKeyMissing:
+		if d.X == nil {
+			return fmt.Sprintf("%s is invalid in x", path.String())
+		}
+		return fmt.Sprintf("%s is invalid in y", path.String())
+	default:
+		return fmt.Sprintf("%s are not equal, where in x is %v but in y is %v", path.String(), d.X, d.Y)
+	}
+}
+
+// DefaultMaxDiffs bounds the number of Diffs collected by
+// DeepEqualExplainedDiff when no WithMaxDiffs option is given.
+const DefaultMaxDiffs = 100
+
+// WithMaxDiffs caps the number of Diffs DeepEqualExplainedDiff collects
+// before it stops descending into further mismatches.
+func WithMaxDiffs(max int) Option {
+	return func(c *config) {
+		c.maxDiffs = max
+	}
+}
+
+func (c *config) maxDiffsOrDefault() int {
+	if c == nil || c.maxDiffs <= 0 {
+		return DefaultMaxDiffs
+	}
+	return c.maxDiffs
+}
+
+// diffSink accumulates Diffs found during a single comparison, up to a cap.
+type diffSink struct {
+	diffs []Diff
+	max   int
+}
+
+func (s *diffSink) full() bool {
+	return len(s.diffs) >= s.max
+}
+
+func (s *diffSink) add(path []PathStep, reason Reason, x, y interface{}) {
+	if s.full() {
+		return
+	}
+	cp := append([]PathStep(nil), path...)
+	s.diffs = append(s.diffs, Diff{Path: cp, Reason: reason, X: x, Y: y})
+}
+
+// DeepEqualExplainedDiff compares x and y and returns every Diff found (up
+// to DefaultMaxDiffs, or the limit set by WithMaxDiffs), rather than just
+// the first one. This is meant for test frameworks that want to render a
+// full side-by-side diff or filter findings by path prefix.
+func DeepEqualExplainedDiff(x, y interface{}, opts ...Option) ([]Diff, error) {
+	cfg := newConfig(opts)
+	if x == nil || y == nil {
+		if x == y {
+			return nil, nil
+		}
+		sink := &diffSink{max: cfg.maxDiffsOrDefault()}
+		sink.add(nil, NilMismatch, x, y)
+		return sink.diffs, nil
+	}
+	v1 := reflect.ValueOf(x)
+	v2 := reflect.ValueOf(y)
+	if v1.Type() != v2.Type() {
+		return []Diff{{Reason: TypeMismatch, X: v1.Type().Name(), Y: v2.Type().Name()}}, nil
+	}
+	sink := &diffSink{max: cfg.maxDiffsOrDefault()}
+	deepValueEqual(v1, v2, make(map[visit]bool), nil, nil, cfg, sink)
+	return sink.diffs, nil
+}
@@ -0,0 +1,44 @@
+package deepequalexplained
+
+import "testing"
+
+type threeFields struct {
+	A, B, C int
+}
+
+func TestDeepEqualExplainedDiffCollectsAll(t *testing.T) {
+	x := threeFields{A: 1, B: 2, C: 3}
+	y := threeFields{A: 9, B: 2, C: 9}
+	diffs, err := DeepEqualExplainedDiff(x, y)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("want 2 diffs, got %d: %v", len(diffs), diffs)
+	}
+}
+
+func TestDeepEqualExplainedDiffEqual(t *testing.T) {
+	diffs, err := DeepEqualExplainedDiff(threeFields{1, 2, 3}, threeFields{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("want 0 diffs, got %d: %v", len(diffs), diffs)
+	}
+}
+
+func TestDeepEqualExplainedDiffMaxDiffs(t *testing.T) {
+	x := make([]int, 10)
+	y := make([]int, 10)
+	for i := range y {
+		y[i] = i + 1
+	}
+	diffs, err := DeepEqualExplainedDiff(x, y, WithMaxDiffs(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("want 3 diffs capped by WithMaxDiffs, got %d", len(diffs))
+	}
+}
@@ -0,0 +1,90 @@
+package deepequalexplained
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Equalities is a map from type to a function comparing two values of
+// that type. It mirrors the pattern used by
+// k8s.io/apimachinery/third_party/forked/golang/reflect: callers register
+// custom comparators for types where byte-for-byte equality is too strict,
+// such as time.Time, *big.Int, or generated protobuf messages.
+type Equalities map[reflect.Type]reflect.Value
+
+// NewEqualities builds an Equalities map, ready for AddFunc/AddFuncs calls.
+func NewEqualities() Equalities {
+	return Equalities{}
+}
+
+// AddFunc registers a custom comparison function. eqFunc must be a function
+// taking two arguments of the same type and returning a bool.
+func (e Equalities) AddFunc(eqFunc interface{}) error {
+	fv := reflect.ValueOf(eqFunc)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		return fmt.Errorf("expected func, got: %v", ft)
+	}
+	if ft.NumIn() != 2 {
+		return fmt.Errorf("expected two 'in' params, got: %v", ft)
+	}
+	if ft.NumOut() != 1 {
+		return fmt.Errorf("expected one 'out' param, got: %v", ft)
+	}
+	if ft.In(0) != ft.In(1) {
+		return fmt.Errorf("expected arg 1 and 2 to have the same type, but got %v and %v", ft.In(0), ft.In(1))
+	}
+	var forReturnType bool
+	boolType := reflect.TypeOf(forReturnType)
+	if ft.Out(0) != boolType {
+		return fmt.Errorf("expected bool return, got: %v", ft)
+	}
+	e[ft.In(0)] = fv
+	return nil
+}
+
+// AddFuncs registers a list of comparison functions, stopping at the first
+// one that fails to register.
+func (e Equalities) AddFuncs(funcs ...interface{}) error {
+	for _, f := range funcs {
+		if err := e.AddFunc(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeepEqualExplained behaves like the package-level DeepEqualExplained, but
+// consults e for any type that has a registered comparison function before
+// falling back to the generic reflective comparison.
+func (e Equalities) DeepEqualExplained(x, y interface{}) error {
+	if x == nil || y == nil {
+		if x == y {
+			return nil
+		} else if x == nil {
+			return fmt.Errorf("x is nil while y is not")
+		} else {
+			return fmt.Errorf("y is nil while x is not")
+		}
+	}
+	v1 := reflect.ValueOf(x)
+	v2 := reflect.ValueOf(y)
+	if v1.Type() != v2.Type() {
+		return fmt.Errorf("values have different types, where in x is %v but in y is %v", v1.Type().Name(), v2.Type().Name())
+	}
+	sink := &diffSink{max: 1}
+	deepValueEqual(v1, v2, make(map[visit]bool), nil, e, nil, sink)
+	if len(sink.diffs) > 0 {
+		return fmt.Errorf("values%s", sink.diffs[0].String())
+	}
+	return nil
+}
+
+// funcValueFor returns the registered comparison function for typ, if any.
+func (e Equalities) funcValueFor(typ reflect.Type) (reflect.Value, bool) {
+	if e == nil {
+		return reflect.Value{}, false
+	}
+	fv, ok := e[typ]
+	return fv, ok
+}
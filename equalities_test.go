@@ -0,0 +1,56 @@
+package deepequalexplained
+
+import "testing"
+
+func TestEqualitiesAddFuncValidation(t *testing.T) {
+	eqs := NewEqualities()
+	if err := eqs.AddFunc(func(a int) bool { return true }); err == nil {
+		t.Fatal("expected error for func with one arg")
+	}
+	if err := eqs.AddFunc(func(a, b int) (bool, error) { return true, nil }); err == nil {
+		t.Fatal("expected error for func with two return values")
+	}
+	if err := eqs.AddFunc(func(a int, b string) bool { return true }); err == nil {
+		t.Fatal("expected error for args of different types")
+	}
+	if err := eqs.AddFunc(func(a, b int) int { return 0 }); err == nil {
+		t.Fatal("expected error for non-bool return")
+	}
+	if err := eqs.AddFunc(func(a, b int) bool { return a == b }); err != nil {
+		t.Fatalf("expected valid func to register, got %v", err)
+	}
+}
+
+func TestEqualitiesCustomComparator(t *testing.T) {
+	type box struct{ n int }
+	eqs := NewEqualities()
+	if err := eqs.AddFunc(func(a, b box) bool { return a.n%10 == b.n%10 }); err != nil {
+		t.Fatalf("AddFunc: %v", err)
+	}
+	if err := eqs.DeepEqualExplained(box{n: 1}, box{n: 11}); err != nil {
+		t.Fatalf("expected equal under custom comparator, got %v", err)
+	}
+	if err := eqs.DeepEqualExplained(box{n: 1}, box{n: 2}); err == nil {
+		t.Fatal("expected mismatch under custom comparator")
+	}
+}
+
+type pairWithUnexportedField struct {
+	val int
+}
+
+type holdsPairUnexported struct {
+	p pairWithUnexportedField
+}
+
+func TestEqualitiesSkipUnexportedField(t *testing.T) {
+	eqs := NewEqualities()
+	if err := eqs.AddFunc(func(a, b pairWithUnexportedField) bool { return a.val == b.val }); err != nil {
+		t.Fatalf("AddFunc: %v", err)
+	}
+	x := holdsPairUnexported{p: pairWithUnexportedField{val: 1}}
+	y := holdsPairUnexported{p: pairWithUnexportedField{val: 1}}
+	if err := eqs.DeepEqualExplained(x, y); err != nil {
+		t.Fatalf("expected equal without panic, got %v", err)
+	}
+}
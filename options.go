@@ -0,0 +1,82 @@
+package deepequalexplained
+
+import "reflect"
+
+// config holds the settings assembled from a list of Option values. A nil
+// *config (the zero-configuration case) must behave identically to the
+// pre-Option DeepEqualExplained.
+type config struct {
+	ignoreStructTag string
+	floatTolerance  *float64
+	nanEqual        bool
+	unorderedSlices map[reflect.Type]bool
+	maxDiffs        int
+}
+
+// Option configures the behavior of DeepEqualExplainedWithOptions.
+type Option func(*config)
+
+// WithIgnoreStructTag skips struct fields whose tag, looked up by tagKey,
+// has the value "-". For example WithIgnoreStructTag("deepequal") honors
+// a field tag of `deepequal:"-"`, which is useful for ignoring caches or
+// mutexes embedded in otherwise-comparable domain types.
+func WithIgnoreStructTag(tagKey string) Option {
+	return func(c *config) {
+		c.ignoreStructTag = tagKey
+	}
+}
+
+// WithFloatTolerance compares reflect.Float32 and reflect.Float64 values
+// with math.Abs(a-b) <= eps instead of requiring exact equality.
+func WithFloatTolerance(eps float64) Option {
+	return func(c *config) {
+		c.floatTolerance = &eps
+	}
+}
+
+// WithNaNEqual opts in to treating NaN as equal to NaN, rather than the
+// default behavior of NaN never being equal to anything.
+func WithNaNEqual() Option {
+	return func(c *config) {
+		c.nanEqual = true
+	}
+}
+
+// WithUnorderedSlices compares slices of the given types as multisets
+// instead of comparing elements index by index.
+func WithUnorderedSlices(types ...reflect.Type) Option {
+	return func(c *config) {
+		if c.unorderedSlices == nil {
+			c.unorderedSlices = make(map[reflect.Type]bool, len(types))
+		}
+		for _, t := range types {
+			c.unorderedSlices[t] = true
+		}
+	}
+}
+
+func newConfig(opts []Option) *config {
+	if len(opts) == 0 {
+		return nil
+	}
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ignoresField reports whether cfg is configured to skip field via a
+// "-" struct tag.
+func (c *config) ignoresField(field reflect.StructField) bool {
+	if c == nil || c.ignoreStructTag == "" {
+		return false
+	}
+	return field.Tag.Get(c.ignoreStructTag) == "-"
+}
+
+// unorderedSlice reports whether slices of typ should be compared as
+// multisets rather than element by element.
+func (c *config) unorderedSlice(typ reflect.Type) bool {
+	return c != nil && c.unorderedSlices[typ]
+}
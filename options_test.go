@@ -0,0 +1,93 @@
+package deepequalexplained
+
+import (
+	"reflect"
+	"testing"
+)
+
+type withIgnoredField struct {
+	A int
+	B int `deepequal:"-"`
+}
+
+func TestWithIgnoreStructTag(t *testing.T) {
+	x := withIgnoredField{A: 1, B: 2}
+	y := withIgnoredField{A: 1, B: 999}
+	if err := DeepEqualExplainedWithOptions(x, y, WithIgnoreStructTag("deepequal")); err != nil {
+		t.Fatalf("expected equal with ignored field, got %v", err)
+	}
+	if err := DeepEqualExplainedWithOptions(x, y); err == nil {
+		t.Fatal("expected mismatch without the option")
+	}
+}
+
+func TestWithFloatTolerance(t *testing.T) {
+	if err := DeepEqualExplainedWithOptions(1.0, 1.0000001, WithFloatTolerance(1e-6)); err != nil {
+		t.Fatalf("expected equal within tolerance, got %v", err)
+	}
+	if err := DeepEqualExplainedWithOptions(1.0, 1.1, WithFloatTolerance(1e-6)); err == nil {
+		t.Fatal("expected mismatch outside tolerance")
+	}
+}
+
+func TestWithNaNEqual(t *testing.T) {
+	nan := 0.0
+	nan = nan / nan
+	if err := DeepEqualExplainedWithOptions(nan, nan, WithNaNEqual()); err != nil {
+		t.Fatalf("expected NaN == NaN with option, got %v", err)
+	}
+	if err := DeepEqualExplainedWithOptions(nan, nan); err == nil {
+		t.Fatal("expected NaN != NaN without the option")
+	}
+}
+
+func TestWithUnorderedSlices(t *testing.T) {
+	x := []int{1, 2, 3}
+	y := []int{3, 1, 2}
+	typ := reflect.TypeOf([]int{})
+	if err := DeepEqualExplainedWithOptions(x, y, WithUnorderedSlices(typ)); err != nil {
+		t.Fatalf("expected equal as multisets, got %v", err)
+	}
+	if err := DeepEqualExplainedWithOptions(x, y); err == nil {
+		t.Fatal("expected mismatch for ordered comparison")
+	}
+	if err := DeepEqualExplainedWithOptions([]int{1, 2}, []int{1, 1}, WithUnorderedSlices(typ)); err == nil {
+		t.Fatal("expected mismatch for unmatched multiset element")
+	}
+}
+
+type ring struct {
+	Items []*ring
+}
+
+func TestWithUnorderedSlicesCycle(t *testing.T) {
+	a := &ring{}
+	a.Items = []*ring{a}
+	b := &ring{}
+	b.Items = []*ring{b}
+	typ := reflect.TypeOf([]*ring{})
+	if err := DeepEqualExplainedWithOptions(a, b, WithUnorderedSlices(typ)); err != nil {
+		t.Fatalf("expected equal cyclic rings, got %v", err)
+	}
+}
+
+type pointee struct {
+	V   int
+	Tag []string
+}
+
+type pointeeHolder struct{ P *pointee }
+
+// A rejected candidate probe must not leave its pointer pairs marked
+// visited in the shared map, or a later, unrelated candidate pair that
+// reuses one of those pointers gets short-circuited to "equal".
+func TestWithUnorderedSlicesRejectedProbeDoesNotLeakVisited(t *testing.T) {
+	shared := &pointee{V: 1, Tag: []string{"t"}}
+	other := &pointee{V: 9, Tag: []string{"t"}}
+	x := []*pointeeHolder{{P: shared}, {P: shared}}
+	y := []*pointeeHolder{{P: other}, {P: shared}}
+	typ := reflect.TypeOf([]*pointeeHolder{})
+	if err := DeepEqualExplainedWithOptions(x, y, WithUnorderedSlices(typ)); err == nil {
+		t.Fatal("expected mismatch, got nil error")
+	}
+}